@@ -0,0 +1,86 @@
+package lightsail
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// awsCredentials is the indirection point that lets the driver's
+// credential-building logic be swapped out in tests via
+// Driver.awsCredentialsFactory.
+type awsCredentials interface {
+	Credentials() *credentials.Credentials
+}
+
+// awsCredentialsOptions describes how to build the AWS credential chain
+// used to talk to Lightsail.
+type awsCredentialsOptions struct {
+	AccessKey       string
+	SecretKey       string
+	SessionToken    string
+	Region          string
+	Profile         string
+	AssumeRoleArn   string
+	ExternalId      string
+	RoleSessionName string
+}
+
+type chainAWSCredentials struct {
+	options awsCredentialsOptions
+}
+
+// NewAWSCredentials builds an awsCredentials backed by, in order: the
+// static --lightsail-aws-access-key/--lightsail-aws-secret-key flags
+// (when given), environment variables, the shared credentials file
+// (honouring --lightsail-profile), and the EC2/ECS instance role. When
+// --lightsail-assume-role-arn is set, that chain is used only to assume
+// the given role via STS, and the resulting temporary credentials are
+// what's actually returned.
+func NewAWSCredentials(options awsCredentialsOptions) awsCredentials {
+	return &chainAWSCredentials{options: options}
+}
+
+func (c *chainAWSCredentials) Credentials() *credentials.Credentials {
+	creds := c.defaultChainCredentials()
+	if c.options.AssumeRoleArn == "" {
+		return creds
+	}
+
+	assumeRoleSession := session.Must(session.NewSession(
+		aws.NewConfig().WithRegion(c.options.Region).WithCredentials(creds),
+	))
+	return stscreds.NewCredentials(assumeRoleSession, c.options.AssumeRoleArn, func(p *stscreds.AssumeRoleProvider) {
+		if c.options.ExternalId != "" {
+			p.ExternalID = aws.String(c.options.ExternalId)
+		}
+		if c.options.RoleSessionName != "" {
+			p.RoleSessionName = c.options.RoleSessionName
+		}
+	})
+}
+
+// defaultChainCredentials mirrors the AWS SDK's own default provider
+// chain, but with the driver's static access/secret key flags given
+// top priority ahead of the environment.
+func (c *chainAWSCredentials) defaultChainCredentials() *credentials.Credentials {
+	var providers []credentials.Provider
+	if c.options.AccessKey != "" || c.options.SecretKey != "" {
+		providers = append(providers, &credentials.StaticProvider{
+			Value: credentials.Value{
+				AccessKeyID:     c.options.AccessKey,
+				SecretAccessKey: c.options.SecretKey,
+				SessionToken:    c.options.SessionToken,
+			},
+		})
+	}
+	providers = append(providers, &credentials.EnvProvider{})
+	providers = append(providers, &credentials.SharedCredentialsProvider{Profile: c.options.Profile})
+
+	cfg := aws.NewConfig().WithRegion(c.options.Region)
+	providers = append(providers, defaults.RemoteCredProvider(*cfg, defaults.Handlers()))
+
+	return credentials.NewChainCredentials(providers)
+}
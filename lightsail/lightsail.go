@@ -37,11 +37,25 @@ type Driver struct {
 	AwsAccessKey          string
 	AwsSecretKey          string
 	AwsSessionToken       string
+	AwsProfile            string
+	AwsAssumeRoleArn      string
+	AwsExternalId         string
+	AwsRoleSessionName    string
 	Region                string
 	BundleId              string
 	BlueprintId           string
 	AvailabilityZone      string
 	InstanceName          string
+	OpenPorts             []string
+	Cidrs                 []string
+	StaticIpName          string
+	AllocateStaticIp      bool
+	StaticIpAllocated     bool
+	UserData              string
+	Tags                  []string
+	AutoSnapshot          bool
+	SnapshotTime          string
+	RestoreFromSnapshot   string
 }
 
 const (
@@ -76,6 +90,26 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Lightsail AWS Secret Key",
 			EnvVar: "LIGHTSAIL_AWS_SECRET_KEY",
 		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-profile",
+			Usage:  "AWS shared credentials file profile to use when no access/secret key is given",
+			EnvVar: "LIGHTSAIL_PROFILE",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-assume-role-arn",
+			Usage:  "ARN of an IAM role to assume via STS before talking to Lightsail",
+			EnvVar: "LIGHTSAIL_ASSUME_ROLE_ARN",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-external-id",
+			Usage:  "External ID to pass when assuming --lightsail-assume-role-arn",
+			EnvVar: "LIGHTSAIL_EXTERNAL_ID",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-role-session-name",
+			Usage:  "Session name to use when assuming --lightsail-assume-role-arn",
+			EnvVar: "LIGHTSAIL_ROLE_SESSION_NAME",
+		},
 		mcnflag.StringFlag{
 			Name:   "lightsail-ssh-key",
 			Usage:  "SSH private key path (if not provided, default SSH key will be used)",
@@ -106,16 +140,88 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  defaultBundleId,
 			EnvVar: "LIGHTSAIL_BUNDLE_ID",
 		},
+		mcnflag.StringSliceFlag{
+			Name:   "lightsail-open-port",
+			Usage:  "Make the specified port number accessible from the Internet, e.g. `--lightsail-open-port 2376/tcp` or `--lightsail-open-port 443/tcp:0.0.0.0/0,10.0.0.0/8` (repeatable, default: SSH and the Docker engine port)",
+			Value:  defaultOpenPorts,
+			EnvVar: "LIGHTSAIL_OPEN_PORT",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "lightsail-cidr",
+			Usage:  "Source CIDR allowed to reach any --lightsail-open-port that does not carry its own CIDR suffix (repeatable)",
+			Value:  defaultCidrs,
+			EnvVar: "LIGHTSAIL_CIDR",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-static-ip-name",
+			Usage:  "Name of an existing Lightsail static IP to attach to the instance",
+			EnvVar: "LIGHTSAIL_STATIC_IP_NAME",
+		},
+		mcnflag.BoolFlag{
+			Name:   "lightsail-allocate-static-ip",
+			Usage:  "Allocate and attach a new Lightsail static IP so the instance's address survives stop/start cycles",
+			EnvVar: "LIGHTSAIL_ALLOCATE_STATIC_IP",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-user-data",
+			Usage:  "Inline cloud-init/user-data script to run on first boot",
+			EnvVar: "LIGHTSAIL_USER_DATA",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-user-data-file",
+			Usage:  "Path to a cloud-init/user-data script to run on first boot (overrides --lightsail-user-data)",
+			EnvVar: "LIGHTSAIL_USER_DATA_FILE",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "lightsail-tag",
+			Usage:  "Tag (key=value) to apply to the instance and key pair (repeatable); a docker-machine-name tag is always added",
+			EnvVar: "LIGHTSAIL_TAG",
+		},
+		mcnflag.BoolFlag{
+			Name:   "lightsail-auto-snapshot",
+			Usage:  "Enable Lightsail's automatic daily instance snapshots",
+			EnvVar: "LIGHTSAIL_AUTO_SNAPSHOT",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-snapshot-time",
+			Usage:  "Daily UTC time (HH:00) at which automatic snapshots are taken, used with --lightsail-auto-snapshot",
+			EnvVar: "LIGHTSAIL_SNAPSHOT_TIME",
+		},
+		mcnflag.StringFlag{
+			Name:   "lightsail-restore-from-snapshot",
+			Usage:  "Name of an existing instance snapshot to restore from instead of creating a blank instance",
+			EnvVar: "LIGHTSAIL_RESTORE_FROM_SNAPSHOT",
+		},
 	}
 }
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SSHPrivateKey = flags.String("lightsail-ssh-key")
 	d.AwsAccessKey = flags.String("lightsail-aws-access-key")
 	d.AwsSecretKey = flags.String("lightsail-aws-secret-key")
+	d.AwsProfile = flags.String("lightsail-profile")
+	d.AwsAssumeRoleArn = flags.String("lightsail-assume-role-arn")
+	d.AwsExternalId = flags.String("lightsail-external-id")
+	d.AwsRoleSessionName = flags.String("lightsail-role-session-name")
 	d.Region = flags.String("lightsail-region")
 	d.AvailabilityZone = flags.String("lightsail-availability-zone")
 	d.BlueprintId = flags.String("lightsail-blueprint-id")
 	d.BundleId = flags.String("lightsail-bundle-id")
+	d.OpenPorts = flags.StringSlice("lightsail-open-port")
+	d.Cidrs = flags.StringSlice("lightsail-cidr")
+	d.StaticIpName = flags.String("lightsail-static-ip-name")
+	d.AllocateStaticIp = flags.Bool("lightsail-allocate-static-ip")
+	d.UserData = flags.String("lightsail-user-data")
+	if userDataFile := flags.String("lightsail-user-data-file"); userDataFile != "" {
+		content, err := ioutil.ReadFile(userDataFile)
+		if err != nil {
+			return err
+		}
+		d.UserData = string(content)
+	}
+	d.Tags = flags.StringSlice("lightsail-tag")
+	d.AutoSnapshot = flags.Bool("lightsail-auto-snapshot")
+	d.SnapshotTime = flags.String("lightsail-snapshot-time")
+	d.RestoreFromSnapshot = flags.String("lightsail-restore-from-snapshot")
 
 	if _, err := d.awsCredentialsFactory().Credentials().Get(); err != nil {
 		return errorMissingCredentials
@@ -199,7 +305,16 @@ func (d *Driver) buildClient() *lightsail.Lightsail {
 	return lightsail.New(session.Must(session.NewSession(config)))
 }
 func (d *Driver) buildCredentials() awsCredentials {
-	return NewAWSCredentials(d.AwsAccessKey, d.AwsSecretKey, d.AwsSessionToken)
+	return NewAWSCredentials(awsCredentialsOptions{
+		AccessKey:       d.AwsAccessKey,
+		SecretKey:       d.AwsSecretKey,
+		SessionToken:    d.AwsSessionToken,
+		Region:          d.Region,
+		Profile:         d.AwsProfile,
+		AssumeRoleArn:   d.AwsAssumeRoleArn,
+		ExternalId:      d.AwsExternalId,
+		RoleSessionName: d.AwsRoleSessionName,
+	})
 }
 func (d *Driver) getClient() *lightsail.Lightsail {
 	return d.clientFactory()
@@ -272,6 +387,17 @@ func (d *Driver) importKeyPairToLightsail() error {
 	}); err != nil {
 		return err
 	}
+	// ImportKeyPair has no Tags field, so tag the key pair afterwards.
+	tags, err := d.buildTags()
+	if err != nil {
+		return err
+	}
+	if _, err := d.getClient().TagResource(&lightsail.TagResourceInput{
+		ResourceName: &d.KeyPairName,
+		Tags:         tags,
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 func (d *Driver) processSSHKey() error {
@@ -322,6 +448,10 @@ func (d *Driver) innerCreate() error {
 	d.SSHUser = *result.Instance.Username
 	// Set IPAddress
 	d.IPAddress = *result.Instance.PublicIpAddress
+	// Allocate/attach the static IP, if requested
+	if err := d.attachStaticIp(); err != nil {
+		return err
+	}
 	// Open ports in lightsail instance
 	if err := d.openPortsInLightsailInstance(); err != nil {
 		return err
@@ -329,16 +459,28 @@ func (d *Driver) innerCreate() error {
 	return nil
 }
 func (d *Driver) openPortsInLightsailInstance() error {
-	log.Infof("Opening port in lightsail instance...")
-	var fromPort, toPort int64 = 0, 65535
-	protocol := "tcp" // tcp, udp, all
-	_, err := d.getClient().OpenInstancePublicPorts(&lightsail.OpenInstancePublicPortsInput{
+	log.Infof("Opening ports in lightsail instance...")
+	openPorts := d.OpenPorts
+	if len(openPorts) == 0 {
+		openPorts = defaultOpenPorts
+	}
+	cidrs := d.Cidrs
+	if len(cidrs) == 0 {
+		cidrs = defaultCidrs
+	}
+
+	var portInfos []*lightsail.PortInfo
+	for _, raw := range openPorts {
+		spec, err := parseOpenPortSpec(raw, cidrs)
+		if err != nil {
+			return err
+		}
+		portInfos = append(portInfos, spec.toPortInfo())
+	}
+
+	_, err := d.getClient().PutInstancePublicPorts(&lightsail.PutInstancePublicPortsInput{
 		InstanceName: &d.InstanceName,
-		PortInfo: &lightsail.PortInfo{
-			FromPort: &fromPort,
-			ToPort:   &toPort,
-			Protocol: &protocol,
-		},
+		PortInfos:    portInfos,
 	})
 	return err
 }
@@ -347,13 +489,47 @@ func (d *Driver) createInstance() error {
 	availabilityZone := fmt.Sprintf("%s%s", d.Region, d.AvailabilityZone)
 	var instanceNames []*string
 	instanceNames = append(instanceNames, &d.InstanceName)
-	if _, err := d.getClient().CreateInstances(&lightsail.CreateInstancesInput{
+
+	tags, err := d.buildTags()
+	if err != nil {
+		return err
+	}
+	addOns := d.buildAddOns()
+
+	if d.RestoreFromSnapshot != "" {
+		log.Infof("Restoring the lightsail instance from snapshot %q...", d.RestoreFromSnapshot)
+		createInstancesFromSnapshotInput := &lightsail.CreateInstancesFromSnapshotInput{
+			AvailabilityZone:     &availabilityZone,
+			InstanceNames:        instanceNames,
+			BundleId:             &d.BundleId,
+			KeyPairName:          &d.KeyPairName,
+			InstanceSnapshotName: &d.RestoreFromSnapshot,
+			AddOns:               addOns,
+			Tags:                 tags,
+		}
+		if d.UserData != "" {
+			createInstancesFromSnapshotInput.UserData = &d.UserData
+		}
+		if _, err := d.getClient().CreateInstancesFromSnapshot(createInstancesFromSnapshotInput); err != nil {
+			return err
+		}
+		log.Infof("The instance has been created")
+		return nil
+	}
+
+	createInstancesInput := &lightsail.CreateInstancesInput{
 		AvailabilityZone: &availabilityZone,
 		InstanceNames:    instanceNames,
 		BlueprintId:      &d.BlueprintId,
 		BundleId:         &d.BundleId,
 		KeyPairName:      &d.KeyPairName,
-	}); err != nil {
+		AddOns:           addOns,
+		Tags:             tags,
+	}
+	if d.UserData != "" {
+		createInstancesInput.UserData = &d.UserData
+	}
+	if _, err := d.getClient().CreateInstances(createInstancesInput); err != nil {
 		return err
 	}
 	log.Infof("The instance has been created")
@@ -407,15 +583,60 @@ func (d *Driver) GetURL() (string, error) {
 	return fmt.Sprintf("tcp://%s", net.JoinHostPort(ip, strconv.Itoa(d.EnginePort))), nil
 }
 
+// lightsailStateCode maps Lightsail's numeric instance state codes (see
+// https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/API_InstanceState.html)
+// to docker-machine's state.State.
+func lightsailStateCode(code int64) state.State {
+	switch code {
+	case 0:
+		return state.Starting
+	case 16:
+		return state.Running
+	case 32, 64:
+		return state.Stopping
+	case 48:
+		return state.Stopped
+	case 80:
+		return state.Stopped
+	default:
+		return state.Error
+	}
+}
+
 func (d *Driver) GetState() (state.State, error) {
-	address := net.JoinHostPort(d.IPAddress, strconv.Itoa(d.SSHPort))
-	_, err := net.DialTimeout("tcp", address, defaultTimeout)
+	result, err := d.getInstanceState()
 	if err != nil {
-		return state.Stopped, nil
+		if isNotFoundErr(err) {
+			return state.Stopped, nil
+		}
+		return state.Error, err
+	}
+	lightsailState := lightsailStateCode(*result.State.Code)
+	if lightsailState != state.Running {
+		return lightsailState, nil
+	}
+	// The API reports the instance as running as soon as it boots; do a
+	// short TCP probe as a secondary confirmation that SSH is actually
+	// reachable before telling docker-machine it can connect.
+	if !d.checkSSHReachable() {
+		return state.Starting, nil
 	}
 	return state.Running, nil
 }
 
+func (d *Driver) checkSSHReachable() bool {
+	if d.IPAddress == "" {
+		return false
+	}
+	address := net.JoinHostPort(d.IPAddress, strconv.Itoa(d.SSHPort))
+	conn, err := net.DialTimeout("tcp", address, defaultTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
 func (d *Driver) Start() error {
 	var startInstanceInput lightsail.StartInstanceInput
 	startInstanceInput.SetInstanceName(d.InstanceName)
@@ -423,7 +644,21 @@ func (d *Driver) Start() error {
 	if err != nil {
 		return err
 	}
-	return d.waitForLightsailInstance()
+	if err := d.waitForLightsailInstance(); err != nil {
+		return err
+	}
+	// Refresh IPAddress: a non-static public IP can change across a
+	// stop/start cycle, so docker-machine needs the current one to
+	// reconnect.
+	result, err := d.getLightsailInstanceInfo()
+	if err != nil {
+		return err
+	}
+	d.IPAddress = *result.Instance.PublicIpAddress
+	// Only report Start() as done once SSH is actually reachable, not
+	// merely once the Lightsail API reports the instance as running.
+	log.Infof("Waiting for SSH to be available...")
+	return mcnutils.WaitFor(d.checkSSHReachable)
 }
 
 func (d *Driver) Stop() error {
@@ -461,6 +696,11 @@ func (d *Driver) Remove() error {
 			break
 		}
 	}
+	// Detach and release the static IP, but only if this driver
+	// allocated it; one attached by name is left for reuse.
+	if err := d.removeStaticIp(); err != nil {
+		return err
+	}
 	for {
 		// Get info of current key pair
 		if _, err := d.getLightsailKeyPairInfo(); err != nil {
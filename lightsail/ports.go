@@ -0,0 +1,105 @@
+package lightsail
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lightsail"
+)
+
+// defaultOpenPorts is the set of ports opened on the instance's public
+// firewall when the user does not supply any --lightsail-open-port
+// flags: SSH and the Docker engine port. Everything else stays closed.
+var defaultOpenPorts = []string{
+	"22/tcp",
+	fmt.Sprintf("%d/tcp", dockerPort),
+}
+
+// defaultCidrs is used for any --lightsail-open-port value that does
+// not carry its own ":CIDR,..." suffix.
+var defaultCidrs = []string{"0.0.0.0/0"}
+
+// openPortSpec is a single parsed --lightsail-open-port entry.
+type openPortSpec struct {
+	FromPort  int64
+	ToPort    int64
+	Protocol  string
+	Cidrs     []string
+	Ipv6Cidrs []string
+}
+
+// parseOpenPortSpec parses a --lightsail-open-port value of the form
+// "PORT[-PORT]/PROTOCOL" or "PORT[-PORT]/PROTOCOL:CIDR[,CIDR...]", e.g.
+// "2376/tcp" or "443/tcp:0.0.0.0/0,10.0.0.0/8". When no CIDR suffix is
+// given, fallbackCidrs is used instead.
+func parseOpenPortSpec(raw string, fallbackCidrs []string) (openPortSpec, error) {
+	portProtocol, cidrsPart := raw, ""
+	if idx := strings.Index(raw, ":"); idx != -1 {
+		portProtocol, cidrsPart = raw[:idx], raw[idx+1:]
+	}
+
+	parts := strings.SplitN(portProtocol, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return openPortSpec{}, fmt.Errorf("invalid --lightsail-open-port value %q, expected PORT[-PORT]/PROTOCOL[:CIDR,...]", raw)
+	}
+
+	fromPort, toPort, err := parsePortRange(parts[0])
+	if err != nil {
+		return openPortSpec{}, fmt.Errorf("invalid --lightsail-open-port value %q: %s", raw, err)
+	}
+
+	cidrs := fallbackCidrs
+	if cidrsPart != "" {
+		cidrs = strings.Split(cidrsPart, ",")
+	}
+
+	var ipv4Cidrs, ipv6Cidrs []string
+	for _, cidr := range cidrs {
+		if strings.Contains(cidr, ":") {
+			ipv6Cidrs = append(ipv6Cidrs, cidr)
+		} else {
+			ipv4Cidrs = append(ipv4Cidrs, cidr)
+		}
+	}
+
+	return openPortSpec{
+		FromPort:  fromPort,
+		ToPort:    toPort,
+		Protocol:  parts[1],
+		Cidrs:     ipv4Cidrs,
+		Ipv6Cidrs: ipv6Cidrs,
+	}, nil
+}
+
+func parsePortRange(raw string) (fromPort, toPort int64, err error) {
+	bounds := strings.SplitN(raw, "-", 2)
+	fromPort, err = strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q: %s", bounds[0], err)
+	}
+	if len(bounds) == 1 {
+		return fromPort, fromPort, nil
+	}
+	toPort, err = strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q: %s", bounds[1], err)
+	}
+	return fromPort, toPort, nil
+}
+
+func (s openPortSpec) toPortInfo() *lightsail.PortInfo {
+	portInfo := &lightsail.PortInfo{
+		FromPort: &s.FromPort,
+		ToPort:   &s.ToPort,
+		Protocol: &s.Protocol,
+	}
+	if len(s.Cidrs) > 0 {
+		portInfo.Cidrs = aws.StringSlice(s.Cidrs)
+	}
+	if len(s.Ipv6Cidrs) > 0 {
+		portInfo.Ipv6Cidrs = aws.StringSlice(s.Ipv6Cidrs)
+	}
+	return portInfo
+}
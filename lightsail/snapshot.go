@@ -0,0 +1,39 @@
+package lightsail
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lightsail"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// buildAddOns turns --lightsail-auto-snapshot/--lightsail-snapshot-time
+// into the AddOns Lightsail expects on instance creation.
+func (d *Driver) buildAddOns() []*lightsail.AddOnRequest {
+	if !d.AutoSnapshot {
+		return nil
+	}
+	addOn := &lightsail.AddOnRequest{
+		AddOnType: aws.String(lightsail.AddOnTypeAutoSnapshot),
+	}
+	if d.SnapshotTime != "" {
+		addOn.AutoSnapshotAddOnRequest = &lightsail.AutoSnapshotAddOnRequest{
+			SnapshotTimeOfDay: &d.SnapshotTime,
+		}
+	}
+	return []*lightsail.AddOnRequest{addOn}
+}
+
+// Snapshot takes an on-demand backup of the instance under
+// snapshotName, so it can later be restored into a new machine via
+// --lightsail-restore-from-snapshot. docker-machine's RPC driver
+// surface only exposes the drivers.Driver interface, so today this is
+// a library-level helper rather than a wired-up "docker-machine
+// snapshot" subcommand.
+func (d *Driver) Snapshot(snapshotName string) error {
+	log.Infof("Creating a snapshot of the lightsail instance...")
+	_, err := d.getClient().CreateInstanceSnapshot(&lightsail.CreateInstanceSnapshotInput{
+		InstanceName:         &d.InstanceName,
+		InstanceSnapshotName: &snapshotName,
+	})
+	return err
+}
@@ -0,0 +1,86 @@
+package lightsail
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lightsail"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// resolveStaticIpName returns the static IP name to use, generating one
+// for --lightsail-allocate-static-ip when --lightsail-static-ip-name
+// was not also given to attach an existing static IP.
+func (d *Driver) resolveStaticIpName() string {
+	if d.StaticIpName != "" {
+		return d.StaticIpName
+	}
+	return fmt.Sprintf("docker_machine_%s_%s", d.MachineName, d.Id)
+}
+
+// attachStaticIp allocates (if requested) and attaches a Lightsail
+// static IP to the instance, then refreshes d.IPAddress with the
+// static address so it survives stop/start cycles.
+func (d *Driver) attachStaticIp() error {
+	if !d.AllocateStaticIp && d.StaticIpName == "" {
+		return nil
+	}
+
+	if d.AllocateStaticIp {
+		d.StaticIpName = d.resolveStaticIpName()
+		log.Infof("Allocating a lightsail static IP...")
+		if _, err := d.getClient().AllocateStaticIp(&lightsail.AllocateStaticIpInput{
+			StaticIpName: &d.StaticIpName,
+		}); err != nil {
+			return err
+		}
+		d.StaticIpAllocated = true
+	}
+
+	log.Infof("Attaching the static IP to the lightsail instance...")
+	if _, err := d.getClient().AttachStaticIp(&lightsail.AttachStaticIpInput{
+		InstanceName: &d.InstanceName,
+		StaticIpName: &d.StaticIpName,
+	}); err != nil {
+		return err
+	}
+
+	result, err := d.getClient().GetStaticIp(&lightsail.GetStaticIpInput{
+		StaticIpName: &d.StaticIpName,
+	})
+	if err != nil {
+		return err
+	}
+	d.IPAddress = *result.StaticIp.IpAddress
+	return nil
+}
+
+// removeStaticIp detaches and releases the static IP, but only when
+// this driver was the one that allocated it; a static IP attached by
+// name via --lightsail-static-ip-name is left alone so it can be
+// reused by another machine.
+func (d *Driver) removeStaticIp() error {
+	if !d.StaticIpAllocated || d.StaticIpName == "" {
+		return nil
+	}
+
+	log.Infof("Detaching the lightsail static IP...")
+	if _, err := d.getClient().DetachStaticIp(&lightsail.DetachStaticIpInput{
+		StaticIpName: &d.StaticIpName,
+	}); err != nil && !isNotFoundErr(err) {
+		return err
+	}
+
+	log.Infof("Releasing the lightsail static IP...")
+	if _, err := d.getClient().ReleaseStaticIp(&lightsail.ReleaseStaticIpInput{
+		StaticIpName: &d.StaticIpName,
+	}); err != nil && !isNotFoundErr(err) {
+		return err
+	}
+	return nil
+}
+
+func isNotFoundErr(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == lightsail.ErrCodeNotFoundException
+}
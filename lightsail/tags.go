@@ -0,0 +1,41 @@
+package lightsail
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lightsail"
+)
+
+// dockerMachineNameTagKey is added automatically to the instance and the
+// key pair this driver creates (static IPs and snapshots are not tagged)
+// so operators can locate them via GetInstances filters
+// or Prometheus Lightsail service discovery
+// (__meta_lightsail_tag_docker-machine-name).
+const dockerMachineNameTagKey = "docker-machine-name"
+
+// parseTag parses a --lightsail-tag value of the form "key=value".
+func parseTag(raw string) (*lightsail.Tag, error) {
+	parts := strings.SplitN(raw, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, fmt.Errorf("invalid --lightsail-tag value %q, expected key=value", raw)
+	}
+	return &lightsail.Tag{Key: aws.String(parts[0]), Value: aws.String(parts[1])}, nil
+}
+
+// buildTags turns d.Tags into the []*lightsail.Tag the Lightsail API
+// expects, adding the automatic docker-machine-name tag.
+func (d *Driver) buildTags() ([]*lightsail.Tag, error) {
+	tags := []*lightsail.Tag{
+		{Key: aws.String(dockerMachineNameTagKey), Value: aws.String(d.MachineName)},
+	}
+	for _, raw := range d.Tags {
+		tag, err := parseTag(raw)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}